@@ -0,0 +1,118 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package astutil
+
+// This file defines utilities for working with source positions.
+
+import (
+	"go/ast"
+	"go/token"
+	"sort"
+)
+
+// PathEnclosingInterval returns the node that encloses the source
+// interval [start, end), and all its ancestors up to the AST root.
+//
+// The zero value is returned if start or end is invalid, if the file
+// doesn't contain them, or if root is not the *ast.File enclosing
+// them.
+//
+// The result is "exact" if the interval is exactly path[0]'s extent,
+// [path[0].Pos(), path[0].End()); otherwise it is "approximate", and
+// path[0] is the tightest node that contains the interval, but may
+// also contain adjacent nodes and whitespace.
+//
+// path[0] is the leaf, the smallest AST node containing the interval,
+// and path[len(path)-1] is root: the result is in leaf-to-root order.
+//
+// Precondition: the ast.Files (all of them) forming root must have
+// been parsed with parser.ParseComments so that comment groups are
+// available for the search.
+func PathEnclosingInterval(root *ast.File, start, end token.Pos) (path []ast.Node, exact bool) {
+	// Precondition: node.[Pos,End) and adjacent whitespace/comments
+	// contain [start, end).
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+		path = append(path, node)
+
+		// Find the child that contains [start, end), if any, and
+		// visit it, thereby refining the path. Comments count as
+		// children too, since a query may fall between two
+		// declarations, in a comment.
+		for _, child := range childrenOf(node) {
+			childPos, childEnd := child.Pos(), child.End()
+			if childPos == token.NoPos || childEnd == token.NoPos {
+				continue
+			}
+			if childPos <= start && end <= childEnd {
+				return visit(child) // child fully contains the interval: descend
+			}
+		}
+
+		// No child fully contains [start, end): node is the
+		// tightest enclosing node found.
+		exact = node.Pos() == start && node.End() == end
+		return true
+	}
+
+	if !tokenRangeContains(root.Pos(), root.End(), start, end) {
+		return nil, false
+	}
+	visit(root)
+
+	// visit appends nodes root-to-leaf; reverse in place so that
+	// path[0] is the leaf, as documented and as required by
+	// consumers such as ssa.EnclosingFunction and ssa.VarValue.
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path, exact
+}
+
+// tokenRangeContains reports whether [start,end) lies within [lo,hi).
+func tokenRangeContains(lo, hi, start, end token.Pos) bool {
+	return lo <= start && end <= hi
+}
+
+// childrenOf returns the direct children of n, in lexical (source)
+// order, including any CommentGroups attached to an *ast.File that
+// ast.Walk does not otherwise visit (it assumes every comment has
+// already been reached via some node's Doc/Comment field, which is
+// not true of a comment that isn't attached to any declaration).
+func childrenOf(n ast.Node) []ast.Node {
+	var children []ast.Node
+
+	// Add child nodes as ast.Inspect would, but only one level deep:
+	// we return false from the visitor for every node but n itself,
+	// so Inspect stops after recording n's direct children.
+	ast.Inspect(n, func(node ast.Node) bool {
+		if node == n {
+			return true // descend once, into n's own children
+		}
+		if node != nil {
+			children = append(children, node)
+		}
+		return false
+	})
+
+	// *ast.File.Comments holds every comment in the file, including
+	// ones not attached to any declaration; splice them into the
+	// child list, in position order, so PathEnclosingInterval can
+	// still descend into one.
+	if file, ok := n.(*ast.File); ok {
+		for _, g := range file.Comments {
+			children = append(children, g)
+		}
+		sort.Sort(byPos(children))
+	}
+
+	return children
+}
+
+type byPos []ast.Node
+
+func (a byPos) Len() int           { return len(a) }
+func (a byPos) Less(i, j int) bool { return a[i].Pos() < a[j].Pos() }
+func (a byPos) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
@@ -0,0 +1,54 @@
+// Copyright 2013 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package astutil
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+const testSrc = `package p
+
+// Doc comment for f.
+func f(x int) int {
+	y := x + 1
+	return y
+}
+`
+
+func TestPathEnclosingInterval(t *testing.T) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "p.go", testSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Locate the "y" in "return y" and check that the tightest
+	// enclosing node is the *ast.Ident itself, and that the path
+	// climbs all the way up to the *ast.File.
+	offset := strings.LastIndex(testSrc, "y")
+	pos := f.Pos() + token.Pos(offset)
+
+	path, exact := PathEnclosingInterval(f, pos, pos+1)
+	if !exact {
+		t.Fatalf("PathEnclosingInterval: got exact=false, want true")
+	}
+	if len(path) == 0 {
+		t.Fatal("PathEnclosingInterval: got empty path")
+	}
+	// path[0] is the leaf (the *ast.Ident itself); path[len-1] is
+	// the *ast.File. Consumers such as ssa.EnclosingFunction and
+	// ssa.VarValue rely on this order, doing e.g.
+	// id := path[0].(*ast.Ident) without a preceding type switch.
+	if _, ok := path[0].(*ast.Ident); !ok {
+		t.Errorf("innermost node = %T, want *ast.Ident", path[0])
+	}
+	if _, ok := path[len(path)-1].(*ast.File); !ok {
+		t.Errorf("outermost node = %T, want *ast.File", path[len(path)-1])
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package callgraph
+
+import (
+	"code.google.com/p/go.tools/go/types"
+	"code.google.com/p/go.tools/ssa"
+)
+
+// CHA computes a call graph for the call sites reachable from the
+// specified packages (and, transitively, their anonymous functions)
+// using the Class Hierarchy Analysis algorithm.
+//
+// For every dynamic call through an interface method m, CHA adds an
+// edge to every concrete function in the program (not just in pkgs)
+// that implements m, via prog.LookupMethod, without regard to whether
+// the call site could actually reach a value of that concrete type.
+// This makes CHA fast but imprecise compared to a points-to-based
+// algorithm. Only named types declared at package level are candidate
+// receivers, and a call through a function value that is not
+// statically known gets no edge, so the graph can omit calls that
+// occur at run time.
+func CHA(prog *ssa.Program, pkgs []*ssa.Package) *Graph {
+	g := New()
+
+	// Candidate concrete types are drawn from the whole program, per
+	// the package doc, not just from pkgs: a type declared outside
+	// pkgs can still be the dynamic type behind an interface value
+	// passed into one of pkgs' functions.
+	var concreteTypes []types.Type
+	for _, pkg := range prog.AllPackages() {
+		for _, mem := range pkg.Members {
+			if t, ok := mem.(*ssa.Type); ok {
+				concreteTypes = append(concreteTypes, t.Type())
+			}
+		}
+	}
+
+	// Only the given packages' functions (and, transitively, their
+	// anonymous functions) are walked for call sites.
+	var funcs []*ssa.Function
+	for _, pkg := range pkgs {
+		for _, mem := range pkg.Members {
+			if fn, ok := mem.(*ssa.Function); ok {
+				funcs = append(funcs, fn)
+			}
+		}
+	}
+
+	for i := 0; i < len(funcs); i++ {
+		fn := funcs[i]
+		caller := g.CreateNode(fn)
+		funcs = append(funcs, fn.AnonFuncs...)
+
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(ssa.CallInstruction)
+				if !ok {
+					continue
+				}
+				common := call.Common()
+				switch {
+				case common.IsInvoke():
+					addInvokeEdges(g, prog, caller, call, concreteTypes)
+				case common.StaticCallee() != nil:
+					AddEdge(caller, call, g.CreateNode(common.StaticCallee()))
+				}
+				// A call through an ordinary (non-invoke) function
+				// value that isn't statically known cannot be
+				// resolved by CHA; it simply has no outgoing edge.
+			}
+		}
+	}
+	return g
+}
+
+// addInvokeEdges adds an edge from caller, at call site, to every
+// concrete function among concreteTypes that implements the interface
+// method named by call's invoke.
+func addInvokeEdges(g *Graph, prog *ssa.Program, caller *Node, call ssa.CallInstruction, concreteTypes []types.Type) {
+	common := call.Common()
+	iface, ok := common.Value.Type().Underlying().(*types.Interface)
+	if !ok {
+		return
+	}
+	for _, T := range concreteTypes {
+		// Interface methods are commonly satisfied by a pointer
+		// receiver even though the declared type in the AST is the
+		// named (non-pointer) type, so probe *T as well as T.
+		PT := types.NewPointer(T)
+		if !prog.ImplementsInterface(T, iface) && !prog.ImplementsInterface(PT, iface) {
+			continue
+		}
+		if callee := prog.LookupMethod(PT, common.Method.Pkg(), common.Method.Name()); callee != nil {
+			AddEdge(caller, call, g.CreateNode(callee))
+		}
+	}
+}
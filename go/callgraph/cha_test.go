@@ -0,0 +1,133 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package callgraph_test
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"code.google.com/p/go.tools/go/callgraph"
+	"code.google.com/p/go.tools/go/loader"
+	"code.google.com/p/go.tools/ssa"
+)
+
+// testSrc exercises the two shapes CHA and Static must each get right:
+// a dynamic call through an interface (Shape.Area), which only CHA can
+// resolve, and a call to a closure created immediately before being
+// invoked, which Static resolves too.
+const testSrc = `package p
+
+type Shape interface {
+	Area() int
+}
+
+type Square struct{ side int }
+
+func (s Square) Area() int { return s.side * s.side }
+
+func Make() Shape { return Square{side: 2} }
+
+func Main() int {
+	add := func(a, b int) int { return a + b }
+	return add(1, 2) + Make().Area()
+}
+`
+
+func buildSSA(t *testing.T) (*ssa.Program, *ssa.Package) {
+	var conf loader.Config
+	conf.Fset = token.NewFileSet()
+	f, err := parser.ParseFile(conf.Fset, "p.go", testSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CreateFromFiles("p", f)
+
+	lprog, err := loader.Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prog := ssa.Create(lprog, ssa.SanityCheckFunctions)
+	prog.BuildAll()
+
+	info := lprog.Created[0]
+	pkg := prog.Package(info.Pkg)
+	if pkg == nil {
+		t.Fatal("no ssa.Package for the created package")
+	}
+	return prog, pkg
+}
+
+func mainFunc(pkg *ssa.Package) *ssa.Function {
+	fn, _ := pkg.Members["Main"].(*ssa.Function)
+	return fn
+}
+
+// callees returns the names of the functions g records as reachable
+// from caller, across every call site in its body.
+func callees(g *callgraph.Graph, caller *ssa.Function) map[string]bool {
+	names := make(map[string]bool)
+	n, ok := g.Nodes[caller]
+	if !ok {
+		return names
+	}
+	for _, e := range n.Out {
+		names[e.Callee.Func.Name()] = true
+	}
+	return names
+}
+
+func TestCHAResolvesInterfaceAndClosureCalls(t *testing.T) {
+	prog, pkg := buildSSA(t)
+	main := mainFunc(pkg)
+	if main == nil {
+		t.Fatal("Main function not found")
+	}
+
+	g := callgraph.CHA(prog, []*ssa.Package{pkg})
+
+	callers := append([]*ssa.Function{main}, main.AnonFuncs...)
+	got := make(map[string]bool)
+	for _, caller := range callers {
+		for name := range callees(g, caller) {
+			got[name] = true
+		}
+	}
+	if !got["Area"] {
+		t.Errorf("CHA did not add an edge to Square.Area through the interface dispatch; got %v", got)
+	}
+	if !anyContains(got, "$1") {
+		t.Errorf("CHA did not add an edge to Main's immediately-invoked closure; got %v", got)
+	}
+}
+
+func TestStaticResolvesClosureCallsOnly(t *testing.T) {
+	_, pkg := buildSSA(t)
+	main := mainFunc(pkg)
+	if main == nil {
+		t.Fatal("Main function not found")
+	}
+
+	g := callgraph.Static([]*ssa.Package{pkg})
+
+	got := callees(g, main)
+	if !anyContains(got, "$1") {
+		t.Errorf("Static did not add an edge to Main's immediately-invoked closure; got %v", got)
+	}
+	if got["Area"] {
+		t.Errorf("Static should not resolve the interface dispatch to Area; got %v", got)
+	}
+}
+
+func anyContains(names map[string]bool, substr string) bool {
+	for name := range names {
+		if strings.Contains(name, substr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package callgraph defines the Graph type, a call graph over
+// ssa.Functions, and provides two algorithms for constructing one:
+//
+//   - CHA implements Class Hierarchy Analysis: it resolves a dynamic
+//     call through an interface method by adding an edge to every
+//     concrete type in the program whose method set implements the
+//     interface, regardless of whether the call site could actually
+//     reach that type at run time. It is fast but imprecise. It is
+//     not complete either: a call through a function value that is
+//     not statically known gets no edge, and only named types
+//     declared at package level are candidate receivers.
+//
+//   - Static builds a call graph containing only statically resolved
+//     edges: calls to a fixed *ssa.Function, and calls to a closure
+//     created immediately before being invoked. It has no false edges
+//     but misses every call made through an interface or a stored
+//     function value.
+//
+// Callers such as a dead-code eliminator or a "callers of" query can
+// be written directly against the resulting Graph, independent of
+// which algorithm produced it.
+package callgraph
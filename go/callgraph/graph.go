@@ -0,0 +1,143 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package callgraph
+
+import (
+	"fmt"
+
+	"code.google.com/p/go.tools/ssa"
+)
+
+// A Graph is a call graph: a set of Nodes denoting functions, with
+// labelled Edges denoting individual call sites.
+//
+// The zero Graph is not ready to use; call New instead.
+type Graph struct {
+	Nodes map[*ssa.Function]*Node
+}
+
+// New returns a new, empty Graph.
+func New() *Graph {
+	return &Graph{Nodes: make(map[*ssa.Function]*Node)}
+}
+
+// CreateNode returns the Node for fn, creating it (with no edges) if
+// this is the first time fn has been seen in g.
+func (g *Graph) CreateNode(fn *ssa.Function) *Node {
+	n, ok := g.Nodes[fn]
+	if !ok {
+		n = &Node{Func: fn, ID: len(g.Nodes)}
+		g.Nodes[fn] = n
+	}
+	return n
+}
+
+// DeleteNode removes n, and every edge into or out of it, from g.
+func (g *Graph) DeleteNode(n *Node) {
+	n.deleteIns()
+	n.deleteOuts()
+	delete(g.Nodes, n.Func)
+}
+
+// AddEdge adds an edge from caller to callee at the call site denoted
+// by site, and returns it.
+func AddEdge(caller *Node, site ssa.CallInstruction, callee *Node) *Edge {
+	e := &Edge{Caller: caller, Site: site, Callee: callee}
+	caller.Out = append(caller.Out, e)
+	callee.In = append(callee.In, e)
+	return e
+}
+
+// A Node is a call graph node: it represents a single *ssa.Function,
+// together with the edges of calls into and out of it.
+type Node struct {
+	Func *ssa.Function
+	ID   int
+	In   []*Edge
+	Out  []*Edge
+}
+
+func (n *Node) String() string {
+	return fmt.Sprintf("n%d:%s", n.ID, n.Func)
+}
+
+func (n *Node) deleteIns() {
+	for _, e := range n.In {
+		removeEdge(&e.Caller.Out, e)
+	}
+	n.In = nil
+}
+
+func (n *Node) deleteOuts() {
+	for _, e := range n.Out {
+		removeEdge(&e.Callee.In, e)
+	}
+	n.Out = nil
+}
+
+// An Edge represents a call from Caller to Callee at the source
+// location denoted by Site.
+type Edge struct {
+	Caller *Node
+	Site   ssa.CallInstruction
+	Callee *Node
+}
+
+func (e *Edge) String() string {
+	return fmt.Sprintf("%s --> %s", e.Caller, e.Callee)
+}
+
+func removeEdge(edges *[]*Edge, edge *Edge) {
+	s := *edges
+	for i, e := range s {
+		if e == edge {
+			s = append(s[:i], s[i+1:]...)
+			break
+		}
+	}
+	*edges = s
+}
+
+// Nodes returns the nodes of g, in no particular order. It exists so
+// that callers built against the Graph type need not reach into its
+// Nodes field directly, which would tie them to the map representation.
+func Nodes(g *Graph) []*Node {
+	nodes := make([]*Node, 0, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// VisitEdges visits every edge reachable from the nodes of g exactly
+// once, calling edge for each. Traversal order is unspecified, but
+// each edge -- including one that closes a cycle -- is visited only
+// once, so callers may freely walk cyclic graphs without looping
+// forever. If edge returns a non-nil error, traversal stops early and
+// VisitEdges returns that error.
+func VisitEdges(g *Graph, edge func(*Edge) error) error {
+	seen := make(map[*Edge]bool)
+	var visit func(n *Node) error
+	visit = func(n *Node) error {
+		for _, e := range n.Out {
+			if !seen[e] {
+				seen[e] = true
+				if err := edge(e); err != nil {
+					return err
+				}
+				if err := visit(e.Callee); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	for _, n := range g.Nodes {
+		if err := visit(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
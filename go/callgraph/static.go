@@ -0,0 +1,55 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package callgraph
+
+import "code.google.com/p/go.tools/ssa"
+
+// Static computes a call graph for the specified packages containing
+// only statically resolvable edges: a call to a fixed *ssa.Function,
+// or a call to a closure created by a *ssa.MakeClosure immediately
+// beforehand. It ignores calls through an interface (invoke mode) and
+// calls through any other function value, so it never contains a
+// false edge but is incomplete for programs that use either.
+func Static(pkgs []*ssa.Package) *Graph {
+	g := New()
+
+	var funcs []*ssa.Function
+	for _, pkg := range pkgs {
+		for _, mem := range pkg.Members {
+			if fn, ok := mem.(*ssa.Function); ok {
+				funcs = append(funcs, fn)
+			}
+		}
+	}
+
+	for i := 0; i < len(funcs); i++ {
+		fn := funcs[i]
+		caller := g.CreateNode(fn)
+		funcs = append(funcs, fn.AnonFuncs...)
+
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				call, ok := instr.(*ssa.Call)
+				if !ok {
+					continue
+				}
+				common := call.Common()
+				if common.IsInvoke() {
+					continue
+				}
+				if callee := common.StaticCallee(); callee != nil {
+					AddEdge(caller, call, g.CreateNode(callee))
+					continue
+				}
+				if mc, ok := common.Value.(*ssa.MakeClosure); ok {
+					if callee, ok := mc.Fn.(*ssa.Function); ok {
+						AddEdge(caller, call, g.CreateNode(callee))
+					}
+				}
+			}
+		}
+	}
+	return g
+}
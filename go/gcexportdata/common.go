@@ -0,0 +1,48 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcexportdata
+
+import "runtime"
+
+// magic identifies the start of a gcexportdata stream. It is not a
+// valid prefix of any earlier gc export data format, so a reader can
+// reject old-format archives outright instead of misinterpreting them.
+const magic = "\x00gcexportdata\n"
+
+// fingerprint is embedded in every stream written by this package and
+// checked by Read. It changes whenever the encoding below changes or
+// the toolchain's type representation does, so that a persistent cache
+// (e.g. one keyed only by source file mtimes) cannot hand a reader
+// export data produced by an incompatible version of the type-checker.
+func fingerprint() string {
+	return runtime.Version() + "/gcexportdata-v2"
+}
+
+// Type tags written to the stream. A tag of 0 is never used so that an
+// accidentally-truncated stream is more likely to be caught as an
+// error than silently misread.
+const (
+	tagNil = iota + 1 // the nil Type
+	tagRef            // reference to an already-written type, by index
+	tagBasic
+	tagArray
+	tagSlice
+	tagStruct
+	tagPointer
+	tagTuple
+	tagSignature
+	tagInterface
+	tagMap
+	tagChan
+	tagNamed
+)
+
+// Object kind tags.
+const (
+	objConst = iota + 1
+	objTypeName
+	objVar
+	objFunc
+)
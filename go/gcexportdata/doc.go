@@ -0,0 +1,29 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gcexportdata provides a compact binary encoding for a
+// type-checked package (a *types.Package together with everything it
+// transitively exports) and a matching decoder that can be plugged
+// into go/types.Config.Import.
+//
+// The encoding is a frame consisting of a short magic string, the
+// toolchain fingerprint that produced it (so that a cache reader can
+// reject export data written by a stale or different build of the
+// type-checker), the package's import path and name, the list of
+// packages it imports, and a body holding the package's exported
+// objects (consts, vars, funcs and named types) together with an index
+// mapping each exported identifier to its byte offset in the body.
+// Each object's encoding is self-contained, so the index doubles as a
+// table of independently-decodable byte ranges: ReadFiltered uses it to
+// decode only the objects a caller actually names (plus the named types
+// those objects refer to), skipping the rest, which matters for a
+// package that exports far more than any one importer references.
+//
+// Use Write to serialize a *types.Package produced by a successful
+// Config.Check, and Read to deserialize it again; Read has the
+// signature required of a Config.Import implementation once its first
+// two arguments are bound. ReadFiltered offers the same decoding with
+// an additional filter for callers, such as the loader, that only need
+// a handful of an import's names.
+package gcexportdata
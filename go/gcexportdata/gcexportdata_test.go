@@ -0,0 +1,153 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcexportdata
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+const testSrc = `package p
+
+const C = 42
+
+type T struct {
+	x int // unexported field
+	Y string
+}
+
+func (t T) M() int { return t.x }
+
+type Node struct {
+	Next *Node // self-referential
+}
+`
+
+func mustCheck(t *testing.T, fset *token.FileSet, src string) *types.Package {
+	f, err := parser.ParseFile(fset, "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conf types.Config
+	pkg, err := conf.Check("p", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// TestRoundTrip checks that Write followed by Read reconstructs a
+// package's consts, its unexported-field struct, its self-referential
+// named type, and (notably) a method's receiver.
+func TestRoundTrip(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := mustCheck(t, fset, testSrc)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, fset, pkg); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	imports := make(map[string]*types.Package)
+	got, err := Read(&buf, fset, imports, "p")
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	c, ok := got.Scope().Lookup("C").(*types.Const)
+	if !ok {
+		t.Fatal("C not found or not a *types.Const")
+	}
+	if c.Val().String() != "42" {
+		t.Errorf("C.Val() = %s, want 42", c.Val().String())
+	}
+
+	tn, ok := got.Scope().Lookup("T").(*types.TypeName)
+	if !ok {
+		t.Fatal("T not found or not a *types.TypeName")
+	}
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		t.Fatal("T is not a *types.Named")
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		t.Fatal("T's underlying type is not a *types.Struct")
+	}
+	if st.NumFields() != 2 {
+		t.Fatalf("T has %d fields, want 2", st.NumFields())
+	}
+	if st.Field(1).Name() != "Y" {
+		t.Errorf("second field = %s, want Y", st.Field(1).Name())
+	}
+
+	if named.NumMethods() != 1 {
+		t.Fatalf("T has %d methods, want 1", named.NumMethods())
+	}
+	m := named.Method(0)
+	if m.Name() != "M" {
+		t.Fatalf("method name = %s, want M", m.Name())
+	}
+	sig, ok := m.Type().(*types.Signature)
+	if !ok {
+		t.Fatal("M's type is not a *types.Signature")
+	}
+	if sig.Recv() == nil {
+		t.Fatal("M's signature lost its receiver in the round trip")
+	}
+	if recvNamed, ok := sig.Recv().Type().(*types.Named); !ok || recvNamed.Obj().Name() != "T" {
+		t.Errorf("M's receiver type = %v, want T", sig.Recv().Type())
+	}
+
+	nodeTn, ok := got.Scope().Lookup("Node").(*types.TypeName)
+	if !ok {
+		t.Fatal("Node not found or not a *types.TypeName")
+	}
+	nodeSt, ok := nodeTn.Type().(*types.Named).Underlying().(*types.Struct)
+	if !ok {
+		t.Fatal("Node's underlying type is not a *types.Struct")
+	}
+	ptr, ok := nodeSt.Field(0).Type().(*types.Pointer)
+	if !ok {
+		t.Fatal("Node.Next is not a pointer")
+	}
+	if ptr.Elem() != nodeTn.Type() {
+		t.Error("Node.Next does not point back to the same *types.Named as Node itself")
+	}
+}
+
+// TestReadFiltered checks that ReadFiltered decodes only the requested
+// names, leaving the rest absent from the resulting package's scope.
+func TestReadFiltered(t *testing.T) {
+	fset := token.NewFileSet()
+	pkg := mustCheck(t, fset, testSrc)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, fset, pkg); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	imports := make(map[string]*types.Package)
+	wanted := map[string]bool{"C": true}
+	got, err := ReadFiltered(&buf, fset, imports, "p", wanted)
+	if err != nil {
+		t.Fatalf("ReadFiltered failed: %v", err)
+	}
+
+	if _, ok := got.Scope().Lookup("C").(*types.Const); !ok {
+		t.Fatal("C not found or not a *types.Const")
+	}
+	if got.Scope().Lookup("T") != nil {
+		t.Error("T was decoded despite not being in wanted")
+	}
+	if got.Scope().Lookup("Node") != nil {
+		t.Error("Node was decoded despite not being in wanted")
+	}
+}
@@ -0,0 +1,384 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcexportdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"strconv"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// Read reads and decodes the gcexportdata stream produced by Write for
+// the package identified by path, resolving any objects that refer to
+// other packages via the imports map: a package already present in
+// imports is reused as-is (so that, say, an *ast.SelectorExpr typed
+// against the real bytes.Buffer continues to work), while a package
+// seen for the first time is synthesized from the (self-contained)
+// stream and recorded into imports, exactly as Config.Import requires.
+//
+// Read decodes every exported object; callers such as the loader, which
+// only need a handful of names out of a package that exports hundreds,
+// should use ReadFiltered instead to skip decoding the rest.
+func Read(r io.Reader, fset *token.FileSet, imports map[string]*types.Package, path string) (*types.Package, error) {
+	return ReadFiltered(r, fset, imports, path, nil)
+}
+
+// ReadFiltered is like Read, but decodes only the objects named by
+// wanted, using the stream's index to locate and decode just those
+// objects' byte ranges. A nil wanted decodes every exported object, as
+// Read does. A named type of the package that a wanted object refers
+// to is decoded too, and is entered into the package's scope even if
+// its own name is not in wanted.
+func ReadFiltered(r io.Reader, fset *token.FileSet, imports map[string]*types.Package, path string, wanted map[string]bool) (*types.Package, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("gcexportdata: read failed: %v", err)
+	}
+
+	rr := &exportReader{buf: bytes.NewReader(data), imports: imports}
+
+	gotMagic := rr.fixed(len(magic))
+	if gotMagic != magic {
+		return nil, fmt.Errorf("gcexportdata: bad magic header for %q", path)
+	}
+
+	if fp := rr.string(); fp != fingerprint() {
+		return nil, fmt.Errorf("gcexportdata: stale export data for %q (fingerprint %q, want %q)", path, fp, fingerprint())
+	}
+
+	pkgPath := rr.string()
+	pkgName := rr.string()
+	if pkgPath == "" {
+		pkgPath = path
+	}
+
+	pkg := imports[pkgPath]
+	if pkg == nil {
+		pkg = types.NewPackage(pkgPath, pkgName)
+		imports[pkgPath] = pkg
+	}
+	rr.pkg = pkg
+
+	numImports := rr.uint64()
+	for i := uint64(0); i < numImports; i++ {
+		rr.string() // informational only: the flattened stream is self-contained
+	}
+
+	type indexEntry struct {
+		name   string
+		offset uint64
+		length uint64
+	}
+	numIndex := rr.uint64()
+	index := make([]indexEntry, numIndex)
+	for i := range index {
+		index[i] = indexEntry{rr.string(), rr.uint64(), rr.uint64()}
+	}
+
+	// Each entry's [offset,length) range, relative to the body that
+	// starts here, decodes independently of every other: Write resets
+	// its type index per object (see Write), so an entry we skip can
+	// never be needed to resolve a tagRef in an entry we decode.
+	bodyStart := int64(len(data)) - int64(rr.buf.Len())
+	for _, e := range index {
+		if wanted != nil && !wanted[e.name] {
+			continue
+		}
+		start := bodyStart + int64(e.offset)
+		or := &exportReader{buf: bytes.NewReader(data[start : start+int64(e.length)]), imports: imports, pkg: pkg}
+		if obj := or.object(); obj != nil {
+			pkg.Scope().Insert(obj)
+		}
+	}
+
+	return pkg, nil
+}
+
+// exportReader is the mirror image of exportWriter: it decodes the
+// stream written by Write, maintaining a parallel table of the
+// non-basic types it has already decoded so that a tagRef can be
+// resolved back to the same Go value the encoder referred to.
+type exportReader struct {
+	buf     *bytes.Reader
+	imports map[string]*types.Package // by import path; shared across the whole Read
+	pkg     *types.Package            // the package currently being decoded
+	typs    []types.Type              // decoded types, indexed as the writer assigned them
+}
+
+func (r *exportReader) fixed(n int) string {
+	b := make([]byte, n)
+	io.ReadFull(r.buf, b)
+	return string(b)
+}
+
+func (r *exportReader) uint64() uint64 {
+	x, _ := binary.ReadUvarint(r.buf)
+	return x
+}
+
+func (r *exportReader) int64() int64 {
+	x, _ := binary.ReadVarint(r.buf)
+	return x
+}
+
+func (r *exportReader) bool() bool {
+	return r.uint64() != 0
+}
+
+func (r *exportReader) string() string {
+	n := r.uint64()
+	return r.fixed(int(n))
+}
+
+func (r *exportReader) pkgByPath(path, name string) *types.Package {
+	if path == "" {
+		path = r.pkg.Path()
+	}
+	if path == r.pkg.Path() {
+		return r.pkg
+	}
+	if pkg, ok := r.imports[path]; ok {
+		return pkg
+	}
+	pkg := types.NewPackage(path, name)
+	r.imports[path] = pkg
+	return pkg
+}
+
+// recv decodes the receiver written by exportWriter.recv, or nil if
+// the encoded signature belongs to a plain function.
+func (r *exportReader) recv() *types.Var {
+	if !r.bool() {
+		return nil
+	}
+	name := r.string()
+	pkgPath := r.string()
+	typ := r.typ()
+	pkg := r.pkgByPath(pkgPath, "")
+	return types.NewVar(token.NoPos, pkg, name, typ)
+}
+
+func (r *exportReader) object() types.Object {
+	kind := r.uint64()
+	name := r.string()
+	switch kind {
+	case objConst:
+		typ := r.typ()
+		val := r.value()
+		return types.NewConst(token.NoPos, r.pkg, name, typ, val)
+
+	case objTypeName:
+		typ := r.typ()
+		if named, ok := typ.(*types.Named); ok {
+			return named.Obj()
+		}
+		return types.NewTypeName(token.NoPos, r.pkg, name, typ)
+
+	case objVar:
+		typ := r.typ()
+		return types.NewVar(token.NoPos, r.pkg, name, typ)
+
+	case objFunc:
+		typ := r.typ()
+		sig, _ := typ.(*types.Signature)
+		return types.NewFunc(token.NoPos, r.pkg, name, sig)
+
+	default:
+		panic(fmt.Sprintf("gcexportdata: unexpected object kind %d", kind))
+	}
+}
+
+func (r *exportReader) value() exact.Value {
+	kind := exact.Kind(r.uint64())
+	lit := r.string()
+	switch kind {
+	case exact.Bool:
+		return exact.MakeBool(lit == "true")
+	case exact.String:
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			s = lit
+		}
+		return exact.MakeString(s)
+	case exact.Int:
+		return exact.MakeFromLiteral(lit, token.INT, 0)
+	case exact.Float:
+		return exact.MakeFromLiteral(lit, token.FLOAT, 0)
+	case exact.Complex:
+		return exact.MakeFromLiteral(lit, token.IMAG, 0)
+	default:
+		return exact.MakeUnknown()
+	}
+}
+
+// typ decodes the next type in the stream. Composite types (everything
+// but tagNil/tagRef/tagBasic/tagNamed) are appended to r.typs as soon
+// as they are fully decoded, in the same order the writer implicitly
+// assigned them indices, so that a later tagRef resolves correctly.
+// tagNamed carries its index explicitly (see exportWriter.typ), since
+// a self-referential named type must be registered before its
+// underlying type is decoded.
+func (r *exportReader) typ() types.Type {
+	tag := r.uint64()
+	switch tag {
+	case tagNil:
+		return nil
+
+	case tagRef:
+		i := r.uint64()
+		return r.typs[i]
+
+	case tagBasic:
+		i := r.uint64()
+		return types.Typ[i]
+
+	case tagNamed:
+		return r.namedTyp()
+	}
+
+	t := r.compositeTyp(tag)
+	r.typs = append(r.typs, t)
+	return t
+}
+
+func (r *exportReader) compositeTyp(tag uint64) types.Type {
+	switch tag {
+	case tagArray:
+		n := r.int64()
+		elem := r.typ()
+		return types.NewArray(elem, n)
+
+	case tagSlice:
+		return types.NewSlice(r.typ())
+
+	case tagStruct:
+		n := r.uint64()
+		fields := make([]*types.Var, n)
+		tags := make([]string, n)
+		for i := uint64(0); i < n; i++ {
+			name := r.string()
+			pkgPath := r.string()
+			typ := r.typ()
+			tag := r.string()
+			anon := r.bool()
+			pkg := r.pkgByPath(pkgPath, "")
+			fields[i] = types.NewField(token.NoPos, pkg, name, typ, anon)
+			tags[i] = tag
+		}
+		return types.NewStruct(fields, tags)
+
+	case tagPointer:
+		return types.NewPointer(r.typ())
+
+	case tagTuple:
+		n := r.uint64()
+		vars := make([]*types.Var, n)
+		for i := uint64(0); i < n; i++ {
+			name := r.string()
+			typ := r.typ()
+			vars[i] = types.NewVar(token.NoPos, r.pkg, name, typ)
+		}
+		return types.NewTuple(vars...)
+
+	case tagSignature:
+		recv := r.recv()
+		// exportWriter.tuple writes params and results without
+		// assigning them an index, so they are decoded here without
+		// being appended to r.typs.
+		r.uint64() // tagTuple
+		params, _ := r.compositeTyp(tagTuple).(*types.Tuple)
+		r.uint64() // tagTuple
+		results, _ := r.compositeTyp(tagTuple).(*types.Tuple)
+		variadic := r.bool()
+		return types.NewSignature(recv, params, results, variadic)
+
+	case tagInterface:
+		n := r.uint64()
+		methods := make([]*types.Func, n)
+		for i := uint64(0); i < n; i++ {
+			name := r.string()
+			pkgPath := r.string()
+			typ := r.typ()
+			pkg := r.pkgByPath(pkgPath, "")
+			sig, _ := typ.(*types.Signature)
+			methods[i] = types.NewFunc(token.NoPos, pkg, name, sig)
+		}
+		return types.NewInterface(methods)
+
+	case tagMap:
+		key := r.typ()
+		elem := r.typ()
+		return types.NewMap(key, elem)
+
+	case tagChan:
+		dir := types.ChanDir(r.uint64())
+		elem := r.typ()
+		return types.NewChan(dir, elem)
+
+	default:
+		panic(fmt.Sprintf("gcexportdata: unexpected type tag %d", tag))
+	}
+}
+
+// namedTyp decodes a tagNamed entry. Its index is transmitted
+// explicitly (unlike the composite kinds) because a self-referential
+// named type must be resolvable by tagRef before its underlying type
+// has finished decoding.
+func (r *exportReader) namedTyp() types.Type {
+	i := int(r.uint64())
+	for len(r.typs) <= i {
+		r.typs = append(r.typs, nil)
+	}
+	pkgPath := r.string()
+	name := r.string()
+	pkg := r.pkgByPath(pkgPath, "")
+
+	// If this package's scope already has this type name (e.g.
+	// because pkg was supplied ready-made via imports), prefer the
+	// existing object so identities of a shared dependency line up
+	// across every package that imports it.
+	if existing := pkg.Scope().Lookup(name); existing != nil {
+		if tn, ok := existing.(*types.TypeName); ok {
+			r.typs[i] = tn.Type()
+			r.typ() // underlying, discarded: existing is authoritative
+			r.skipMethods()
+			return r.typs[i]
+		}
+	}
+
+	obj := types.NewTypeName(token.NoPos, pkg, name, nil)
+	named := types.NewNamed(obj, nil, nil)
+	r.typs[i] = named
+	underlying := r.typ()
+	named.SetUnderlying(underlying)
+
+	nm := r.uint64()
+	for j := uint64(0); j < nm; j++ {
+		mname := r.string()
+		mtyp := r.typ()
+		sig, _ := mtyp.(*types.Signature)
+		named.AddMethod(types.NewFunc(token.NoPos, pkg, mname, sig))
+	}
+	pkg.Scope().Insert(obj)
+	return named
+}
+
+// skipMethods consumes a method list without materializing it; used
+// when a *types.Named read from the stream turns out to duplicate one
+// already present via the imports map.
+func (r *exportReader) skipMethods() {
+	nm := r.uint64()
+	for j := uint64(0); j < nm; j++ {
+		r.string()
+		r.typ()
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcexportdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go/token"
+	"io"
+
+	"code.google.com/p/go.tools/go/exact"
+	"code.google.com/p/go.tools/go/types"
+)
+
+// Write serializes pkg, which must have been produced by a successful
+// Config.Check, into w. fset is required so that, in principle, export
+// data could carry position information; the current encoding does
+// not, since importers only need types and values, not positions.
+func Write(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
+	header := &exportWriter{}
+	io.WriteString(&header.buf, magic)
+	header.string(fingerprint())
+	header.string(pkg.Path())
+	header.string(pkg.Name())
+
+	imports := pkg.Imports()
+	header.uint64(uint64(len(imports)))
+	for _, imp := range imports {
+		header.string(imp.Path())
+	}
+
+	// Encode each exported object into its own, independently-decodable
+	// blob, appended to a shared body buffer; the index below records
+	// the byte range each one occupies. Objects are visited in scope
+	// order, which is deterministic for a given *types.Package.
+	//
+	// typIndex is reset for every object rather than shared across the
+	// whole body: tagRef indices are only ever used to break a cycle
+	// within the single object currently being written (e.g. a linked
+	// list's self-reference), never to dedup a type across objects.
+	// Dedup of a *types.Named shared by several objects instead falls
+	// out of exportReader.namedTyp consulting pkg.Scope() on the read
+	// side, so resetting typIndex per object costs a little duplicated
+	// encoding of shared types but is what lets Read (and ReadFiltered)
+	// decode any one object's blob in isolation, by offset and length,
+	// without having walked any of the others first.
+	type indexEntry struct {
+		name   string
+		offset uint64
+		length uint64
+	}
+	body := &exportWriter{}
+	scope := pkg.Scope()
+	var index []indexEntry
+	for _, name := range scope.Names() {
+		if !isExported(name) {
+			continue
+		}
+		obj := &exportWriter{typIndex: make(map[types.Type]int)}
+		obj.object(scope.Lookup(name))
+		start := body.buf.Len()
+		body.buf.Write(obj.buf.Bytes())
+		index = append(index, indexEntry{name, uint64(start), uint64(body.buf.Len() - start)})
+	}
+
+	header.uint64(uint64(len(index)))
+	for _, e := range index {
+		header.string(e.name)
+		header.uint64(e.offset)
+		header.uint64(e.length)
+	}
+
+	if _, err := w.Write(header.buf.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(body.buf.Bytes())
+	return err
+}
+
+// isExported reports whether name denotes an exported identifier; it
+// is used instead of ast.IsExported to avoid importing go/ast here.
+func isExported(name string) bool {
+	if name == "" {
+		return false
+	}
+	r := name[0]
+	return 'A' <= r && r <= 'Z'
+}
+
+// An exportWriter accumulates the binary encoding of a single object's
+// export data. typIndex is local to the object currently being
+// written: it only ever breaks a cycle within that one object's type
+// graph (see Write), never dedups a type across objects.
+type exportWriter struct {
+	buf      bytes.Buffer
+	typIndex map[types.Type]int
+}
+
+func (w *exportWriter) uint64(x uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], x)
+	w.buf.Write(tmp[:n])
+}
+
+func (w *exportWriter) int64(x int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], x)
+	w.buf.Write(tmp[:n])
+}
+
+func (w *exportWriter) string(s string) {
+	w.uint64(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+func (w *exportWriter) bool(b bool) {
+	if b {
+		w.uint64(1)
+	} else {
+		w.uint64(0)
+	}
+}
+
+func (w *exportWriter) object(obj types.Object) {
+	switch obj := obj.(type) {
+	case *types.Const:
+		w.uint64(objConst)
+		w.string(obj.Name())
+		w.typ(obj.Type())
+		w.value(obj.Val())
+
+	case *types.TypeName:
+		w.uint64(objTypeName)
+		w.string(obj.Name())
+		w.typ(obj.Type())
+
+	case *types.Var:
+		w.uint64(objVar)
+		w.string(obj.Name())
+		w.typ(obj.Type())
+
+	case *types.Func:
+		w.uint64(objFunc)
+		w.string(obj.Name())
+		w.typ(obj.Type())
+
+	default:
+		panic("gcexportdata: unexpected object kind")
+	}
+}
+
+func (w *exportWriter) value(v exact.Value) {
+	w.uint64(uint64(v.Kind()))
+	w.string(v.String())
+}
+
+// typ encodes t, memoizing every non-basic type it writes within the
+// current object, so that a repeat occurrence of the same type (such as
+// a named type's reference back to itself) is written as a tagRef to a
+// small integer index rather than encoded again.
+func (w *exportWriter) typ(t types.Type) {
+	if t == nil {
+		w.uint64(tagNil)
+		return
+	}
+	if i, ok := w.typIndex[t]; ok {
+		w.uint64(tagRef)
+		w.uint64(uint64(i))
+		return
+	}
+
+	switch t := t.(type) {
+	case *types.Basic:
+		if i, ok := basicIndex(t); ok {
+			w.uint64(tagBasic)
+			w.uint64(uint64(i))
+			return
+		}
+		panic("gcexportdata: unknown basic type")
+
+	case *types.Array:
+		w.uint64(tagArray)
+		w.int64(t.Len())
+		w.typ(t.Elem())
+
+	case *types.Slice:
+		w.uint64(tagSlice)
+		w.typ(t.Elem())
+
+	case *types.Struct:
+		w.uint64(tagStruct)
+		n := t.NumFields()
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			f := t.Field(i)
+			w.string(f.Name())
+			if !f.Exported() {
+				w.string(f.Pkg().Path())
+			} else {
+				w.string("")
+			}
+			w.typ(f.Type())
+			w.string(t.Tag(i))
+			w.bool(f.Anonymous())
+		}
+
+	case *types.Pointer:
+		w.uint64(tagPointer)
+		w.typ(t.Elem())
+
+	case *types.Tuple:
+		w.tuple(t)
+
+	case *types.Signature:
+		w.uint64(tagSignature)
+		w.recv(t.Recv())
+		w.tuple(t.Params())
+		w.tuple(t.Results())
+		w.bool(t.Variadic())
+
+	case *types.Interface:
+		w.uint64(tagInterface)
+		n := t.NumMethods()
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			m := t.Method(i)
+			w.string(m.Name())
+			if !m.Exported() {
+				w.string(m.Pkg().Path())
+			} else {
+				w.string("")
+			}
+			w.typ(m.Type())
+		}
+
+	case *types.Map:
+		w.uint64(tagMap)
+		w.typ(t.Key())
+		w.typ(t.Elem())
+
+	case *types.Chan:
+		w.uint64(tagChan)
+		w.uint64(uint64(t.Dir()))
+		w.typ(t.Elem())
+
+	case *types.Named:
+		// Reserve the index before recursing so that a type that
+		// refers back to itself (e.g. a linked-list node) encodes
+		// as a tagRef rather than recursing forever.
+		i := len(w.typIndex)
+		w.typIndex[t] = i
+		w.uint64(tagNamed)
+		w.uint64(uint64(i))
+		obj := t.Obj()
+		w.string(obj.Pkg().Path())
+		w.string(obj.Name())
+		w.typ(t.Underlying())
+		n := t.NumMethods()
+		w.uint64(uint64(n))
+		for i := 0; i < n; i++ {
+			m := t.Method(i)
+			w.string(m.Name())
+			w.typ(m.Type())
+		}
+		return
+
+	default:
+		panic("gcexportdata: unexpected type")
+	}
+
+	i := len(w.typIndex)
+	w.typIndex[t] = i
+}
+
+// recv encodes a method's receiver, or its absence for a plain
+// function type. It must be written explicitly: unlike a *types.Named,
+// a *types.Signature carries no other trace of Recv() that a reader
+// could reconstruct on its own.
+func (w *exportWriter) recv(v *types.Var) {
+	if v == nil {
+		w.bool(false)
+		return
+	}
+	w.bool(true)
+	w.string(v.Name())
+	w.string(v.Pkg().Path())
+	w.typ(v.Type())
+}
+
+func (w *exportWriter) tuple(t *types.Tuple) {
+	w.uint64(tagTuple)
+	n := 0
+	if t != nil {
+		n = t.Len()
+	}
+	w.uint64(uint64(n))
+	for i := 0; i < n; i++ {
+		v := t.At(i)
+		w.string(v.Name())
+		w.typ(v.Type())
+	}
+}
+
+// basicIndex returns the index of b within types.Typ, the fixed table
+// of predeclared basic types, so that basic types can be referred to
+// compactly by a single small integer rather than re-encoded.
+func basicIndex(b *types.Basic) (int, bool) {
+	for i, t := range types.Typ {
+		if t == b {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loader
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// processCgoFiles invokes the cgo tool on bp's CgoFiles in a fresh
+// temporary directory, then parses the "_cgo_gotypes.go" file that cgo
+// writes there (which contains declarations for all the cgo-generated
+// stubs and the types of the annotated "C" identifiers) and returns it
+// as a single *ast.File, ready to be type-checked alongside the
+// package's ordinary .go files.
+//
+// readFile is consulted for the content of each cgo source file before
+// it is copied into the temporary directory, so that overlaid (e.g.
+// unsaved editor buffer) contents are honored just as they are for
+// ordinary files.
+func processCgoFiles(bp *build.Package, fset *token.FileSet, readFile func(string) []byte) ([]*ast.File, error) {
+	tmpdir, err := ioutil.TempDir("", "go-loader-cgo")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+
+	var cgoFilenames []string
+	for _, name := range bp.CgoFiles {
+		src := filepath.Join(bp.Dir, name)
+		data := readFile(src)
+		if data == nil {
+			data, err = ioutil.ReadFile(src)
+			if err != nil {
+				return nil, err
+			}
+		}
+		dst := filepath.Join(tmpdir, name)
+		if err := ioutil.WriteFile(dst, data, 0644); err != nil {
+			return nil, err
+		}
+		cgoFilenames = append(cgoFilenames, dst)
+	}
+
+	args := append([]string{"tool", "cgo", "-objdir", tmpdir}, cgoFilenames...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = tmpdir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("cgo preprocessing of %s failed: %v\n%s", bp.ImportPath, err, out)
+	}
+
+	gotypes := filepath.Join(tmpdir, "_cgo_gotypes.go")
+	f, err := parser.ParseFile(fset, gotypes, nil, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing cgo output for %s: %v", bp.ImportPath, err)
+	}
+
+	// The generated file's Pos/End information refers to a path that
+	// is about to be deleted; its content has already been fully
+	// parsed into f, so that's fine for type-checking purposes.
+	return []*ast.File{f}, nil
+}
@@ -0,0 +1,30 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package loader loads a complete Go program from source code, parsing
+// and type-checking the initial packages plus their transitive closure
+// of dependencies.  It is a thin, higher-level layer over
+// go/types.Config.Check that does the bookkeeping every real tool
+// (godoc, vet, guru, oracle, ...) needs but that the type-checker itself
+// deliberately leaves to the caller: import graph discovery, ordering
+// packages so that dependencies are checked before their dependents,
+// cgo preprocessing, and support for type-checking unsaved editor
+// buffers via an overlay.
+//
+// A typical client creates a Config, populates it with one or more
+// initial packages (via CreateFromFilenames, CreateFromFiles, or
+// Import), then calls Load to obtain a Program.  The Program holds, for
+// every package that was loaded, its *types.Package, its *types.Info,
+// the FileSet, and the parsed *ast.Files.
+//
+// Packages named explicitly via CreateFromFilenames/CreateFromFiles are
+// "created" packages: they are type-checked from the given syntax
+// regardless of what's on disk.  Packages reached only because some
+// other package imports them are "imported" packages: they are located
+// via the Config's build.Context and parsed from disk.  This
+// distinction matters because a created package may legitimately fail
+// to parse as valid Go (e.g. it's a scratch buffer) while an imported
+// package is expected to be well-formed, and because only created
+// packages may shadow a package of the same import path found on disk.
+package loader
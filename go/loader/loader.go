@@ -0,0 +1,345 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loader
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+// A Config specifies the configuration for loading a whole program from
+// Go source code.  Its zero value, plus a call to one of
+// CreateFromFilenames, CreateFromFiles or Import, is a ready-to-use
+// configuration for the common case.
+type Config struct {
+	// Fset is the file set for the parsed files and the resulting
+	// program.  If nil, Load will create one.
+	Fset *token.FileSet
+
+	// TypeChecker contains options relevant to the type checker.
+	// The supplied Error and Import fields are ignored; Load
+	// installs its own.
+	TypeChecker types.Config
+
+	// TypeCheckFuncBodies, if non-nil, controls which packages'
+	// function bodies are type-checked. If it returns false for a
+	// given import path, the package's function bodies are not
+	// checked, which can save significant work when only the
+	// package's exported API is of interest. If nil, all function
+	// bodies are checked.
+	TypeCheckFuncBodies func(path string) bool
+
+	// Build specifies the build context used to locate source
+	// packages and their dependencies on disk. If nil,
+	// &build.Default is used.
+	Build *build.Context
+
+	// AllowErrors, if true, causes Load to succeed (and populate
+	// the returned Program) even if some packages contained type
+	// or parse errors, and even if a dependency was not found.
+	// The erroneous packages' Errors fields record what went wrong.
+	AllowErrors bool
+
+	// Overlay maps from filename to the file's contents, overriding
+	// the contents of the file when it would otherwise be read from
+	// the file system. It allows clients
+	// (typically editors) to type-check unsaved buffers. Files are
+	// looked up by their absolute path, as returned by
+	// build.Context.Import.
+	Overlay map[string][]byte
+
+	created []PkgSpec       // packages specified by CreateFromFilenames/Files
+	imports map[string]bool // packages specified by Import
+}
+
+// A PkgSpec specifies a single "created" package: one whose syntax is
+// supplied by the client rather than located via the build context.
+type PkgSpec struct {
+	Path  string      // package's import path, e.g. "fmt"
+	Files []*ast.File // ASTs, from parser.ParseFile or similar
+}
+
+// CreateFromFilenames arranges for Load to create a package from the
+// Go source files named by filenames, importable (and type-checked)
+// under the import path path.
+func (conf *Config) CreateFromFilenames(path string, filenames ...string) error {
+	files, err := conf.parseFiles(filenames)
+	if err != nil {
+		return err
+	}
+	conf.CreateFromFiles(path, files...)
+	return nil
+}
+
+// CreateFromFiles arranges for Load to create a package with the
+// specified, already-parsed files, importable under the import path
+// path.
+func (conf *Config) CreateFromFiles(path string, files ...*ast.File) {
+	conf.created = append(conf.created, PkgSpec{Path: path, Files: files})
+}
+
+// Import arranges for Load to load the package identified by path,
+// plus its transitive dependencies, from source found via the build
+// context.
+func (conf *Config) Import(path string) {
+	if conf.imports == nil {
+		conf.imports = make(map[string]bool)
+	}
+	conf.imports[path] = true
+}
+
+func (conf *Config) parseFiles(filenames []string) ([]*ast.File, error) {
+	var files []*ast.File
+	for _, filename := range filenames {
+		file, err := parser.ParseFile(conf.fset(), filename, conf.readFile(filename), parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// readFile returns the overlaid contents of filename, if any, or nil
+// to let the parser read the file itself.
+func (conf *Config) readFile(filename string) []byte {
+	return conf.Overlay[filename]
+}
+
+func (conf *Config) fset() *token.FileSet {
+	if conf.Fset == nil {
+		conf.Fset = token.NewFileSet()
+	}
+	return conf.Fset
+}
+
+func (conf *Config) build() *build.Context {
+	if conf.Build == nil {
+		conf.Build = &build.Default
+	}
+	return conf.Build
+}
+
+// A PackageInfo holds the ASTs and facts derived by the type-checker
+// for a single package.
+type PackageInfo struct {
+	Pkg                   *types.Package
+	Importable            bool        // true if the package can be imported by its Pkg.Path()
+	TransitivelyErrorFree bool        // true if this package and all its dependencies are free of errors
+	Files                 []*ast.File // syntax trees, in the order given to the type-checker
+	Errors                []error     // non-nil if the package had type or parse errors
+	types.Info                        // type-checking results: Types, Values, Defs, Uses, ...
+}
+
+func (info *PackageInfo) String() string { return info.Pkg.Path() }
+
+func (info *PackageInfo) appendError(err error) {
+	info.Errors = append(info.Errors, err)
+}
+
+// A Program is the result of loading a complete Go program: the
+// transitive closure of the "created" and "imported" packages of a
+// Config, type-checked in dependency order.
+type Program struct {
+	Fset *token.FileSet // file set for all files in the program
+
+	Created []*PackageInfo // the packages specified via CreateFromFilenames/Files, in order
+
+	Imported map[string]*PackageInfo // the packages specified via Import, keyed by path
+
+	AllPackages map[*types.Package]*PackageInfo // all loaded packages, keyed by object
+}
+
+// PackageInfo returns the PackageInfo for the specified package
+// object, or nil if pkg was not part of the loaded program.
+func (prog *Program) PackageInfo(pkg *types.Package) *PackageInfo {
+	return prog.AllPackages[pkg]
+}
+
+// Load creates, parses and type-checks the program specified by conf,
+// returning the resulting Program.
+//
+// Dependencies are loaded, parsed and type-checked before the packages
+// that depend on them, so that a single forward pass suffices. Created
+// packages are type-checked first (in the order they were added to
+// conf), then imported packages are loaded and type-checked as they
+// are discovered as dependencies.
+func Load(conf *Config) (*Program, error) {
+	ld := &loaderState{
+		conf:     conf,
+		fset:     conf.fset(),
+		ctxt:     conf.build(),
+		visiting: make(map[string]bool),
+		packages: make(map[string]*PackageInfo),
+	}
+
+	prog := &Program{
+		Fset:        ld.fset,
+		Imported:    make(map[string]*PackageInfo),
+		AllPackages: make(map[*types.Package]*PackageInfo),
+	}
+
+	// Created packages are checked first, directly from the
+	// caller-supplied syntax; they are never located on disk.
+	for _, spec := range conf.created {
+		info, err := ld.checkPackage(spec.Path, spec.Files, true)
+		if err != nil && !conf.AllowErrors {
+			return nil, err
+		}
+		ld.packages[spec.Path] = info
+		prog.Created = append(prog.Created, info)
+	}
+
+	// Packages named by Import, plus anything they import
+	// transitively, are located via the build context.
+	var paths []string
+	for path := range conf.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths) // for determinism
+	for _, path := range paths {
+		info, err := ld.loadImport(path)
+		if err != nil && !conf.AllowErrors {
+			return nil, err
+		}
+		if info != nil {
+			prog.Imported[path] = info
+		}
+	}
+
+	for _, info := range ld.packages {
+		prog.AllPackages[info.Pkg] = info
+	}
+	return prog, nil
+}
+
+// loaderState carries the state of a single Load call: the packages
+// visited so far (to detect import cycles and avoid duplicate work)
+// and the in-progress result set.
+type loaderState struct {
+	conf     *Config
+	fset     *token.FileSet
+	ctxt     *build.Context
+	visiting map[string]bool         // packages currently being loaded (cycle detection)
+	packages map[string]*PackageInfo // packages already loaded, keyed by import path
+}
+
+// loadImport loads, parses and type-checks the package at path and its
+// dependencies, returning its PackageInfo. It is idempotent: a package
+// already loaded is returned from the cache.
+func (ld *loaderState) loadImport(path string) (*PackageInfo, error) {
+	if info, ok := ld.packages[path]; ok {
+		return info, nil
+	}
+	if ld.visiting[path] {
+		return nil, fmt.Errorf("import cycle detected: %s", path)
+	}
+	ld.visiting[path] = true
+	defer delete(ld.visiting, path)
+
+	bp, err := ld.ctxt.Import(path, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ld.parsePackage(bp)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := ld.checkPackage(path, files, true)
+	ld.packages[path] = info
+	return info, err
+}
+
+// parsePackage parses the non-cgo files of bp in the ordinary way, and
+// preprocesses and parses any cgo files via processCgoFiles.
+func (ld *loaderState) parsePackage(bp *build.Package) ([]*ast.File, error) {
+	var files []*ast.File
+	for _, name := range bp.GoFiles {
+		filename := filepath.Join(bp.Dir, name)
+		file, err := parser.ParseFile(ld.fset, filename, ld.conf.readFile(filename), parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	if len(bp.CgoFiles) > 0 {
+		cgoFiles, err := processCgoFiles(bp, ld.fset, ld.conf.readFile)
+		if err != nil {
+			if !ld.conf.AllowErrors {
+				return nil, err
+			}
+		} else {
+			files = append(files, cgoFiles...)
+		}
+	}
+	return files, nil
+}
+
+// checkPackage type-checks the given files as the package identified
+// by path, recording any errors in the resulting PackageInfo rather
+// than aborting, so that partial results remain available even when
+// AllowErrors is set.
+func (ld *loaderState) checkPackage(path string, files []*ast.File, importable bool) (*PackageInfo, error) {
+	info := &PackageInfo{
+		Importable: importable,
+		Files:      files,
+		Info: types.Info{
+			Types:      make(map[ast.Expr]types.Type),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Scopes:     make(map[ast.Node]*types.Scope),
+		},
+	}
+
+	tc := ld.conf.TypeChecker
+	tc.Error = info.appendError
+	tc.Import = ld.importer
+	if ld.conf.TypeCheckFuncBodies != nil {
+		tc.IgnoreFuncBodies = !ld.conf.TypeCheckFuncBodies(path)
+	}
+
+	pkg, err := tc.Check(path, ld.fset, files, &info.Info)
+	info.Pkg = pkg
+
+	info.TransitivelyErrorFree = len(info.Errors) == 0
+	if pkg != nil {
+		for _, imp := range pkg.Imports() {
+			if dep, ok := ld.packages[imp.Path()]; ok && !dep.TransitivelyErrorFree {
+				info.TransitivelyErrorFree = false
+			}
+		}
+	}
+
+	if len(info.Errors) > 0 && err == nil {
+		err = info.Errors[0]
+	}
+	return info, err
+}
+
+// importer is installed as the type-checker's Config.Import hook; it
+// resolves each imported package by delegating to loadImport, so that
+// the whole transitive closure is loaded and checked exactly once.
+func (ld *loaderState) importer(imports map[string]*types.Package, path string) (*types.Package, error) {
+	if pkg, ok := imports[path]; ok {
+		return pkg, nil
+	}
+	info, err := ld.loadImport(path)
+	if err != nil {
+		return nil, err
+	}
+	imports[path] = info.Pkg
+	return info.Pkg, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package loader
+
+import (
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code.google.com/p/go.tools/go/types"
+)
+
+const aSrc = `package a
+
+import "b"
+
+func F() int { return b.V }
+`
+
+// bStubSrc is what's actually on disk for "b"; bOverlaySrc is what the
+// Overlay substitutes for it. The test distinguishes the two via V's
+// value, to prove Load type-checked the overlaid source, not the file
+// on disk.
+const bStubSrc = `package b
+
+const V = 0
+`
+
+const bOverlaySrc = `package b
+
+const V = 42
+`
+
+// TestLoadCreateFromFilesAndImportWithOverlay exercises the combination
+// CreateFromFiles + Import, resolving a two-package graph where the
+// imported package's file contents come entirely from conf.Overlay
+// rather than disk -- the scenario an editor integration relies on to
+// type-check an unsaved buffer.
+func TestLoadCreateFromFilesAndImportWithOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loader_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bDir := filepath.Join(dir, "src", "b")
+	if err := os.MkdirAll(bDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	bFile := filepath.Join(bDir, "b.go")
+	if err := ioutil.WriteFile(bFile, []byte(bStubSrc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var conf Config
+	conf.Fset = token.NewFileSet()
+	conf.Build = &build.Context{
+		GOROOT:   build.Default.GOROOT,
+		GOPATH:   dir,
+		Compiler: build.Default.Compiler,
+	}
+	conf.Overlay = map[string][]byte{bFile: []byte(bOverlaySrc)}
+
+	f, err := parser.ParseFile(conf.Fset, "a.go", aSrc, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.CreateFromFiles("a", f)
+	conf.Import("b")
+
+	prog, err := Load(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(prog.Created) != 1 || prog.Created[0].Pkg.Path() != "a" {
+		t.Fatalf("Created = %v, want a single package \"a\"", prog.Created)
+	}
+
+	bInfo := prog.Imported["b"]
+	if bInfo == nil {
+		t.Fatal(`"b" was not loaded`)
+	}
+	v, ok := bInfo.Pkg.Scope().Lookup("V").(*types.Const)
+	if !ok {
+		t.Fatal("b.V not found or not a constant")
+	}
+	if got := v.Val().String(); got != "42" {
+		t.Errorf("b.V = %s, want 42 (the overlaid value, not bStubSrc's 0)", got)
+	}
+
+	// "a" itself must be cached under its own import path so that a
+	// package importing "a" (none does here) would hit ld.packages
+	// instead of re-reading it from disk.
+	if _, ok := prog.AllPackages[prog.Created[0].Pkg]; !ok {
+		t.Error("a's PackageInfo is missing from AllPackages")
+	}
+}
@@ -9,8 +9,9 @@
 // Type-checking consists of several interdependent phases:
 //
 // Name resolution maps each identifier (ast.Ident) in the program to the
-// language object (Object) it denotes.
-// Use Info.Objects, Info.Implicits for the results of name resolution.
+// language object (Object) it denotes, distinguishing an identifier's
+// defining occurrence from its uses.
+// Use Info.Defs, Info.Uses, Info.Implicits for the results of name resolution.
 //
 // Constant folding computes the exact constant value (exact.Value) for
 // every expression (ast.Expr) that is a compile-time constant.
@@ -106,14 +107,26 @@ type Info struct {
 	// Values maps constant expressions to their values.
 	Values map[ast.Expr]exact.Value
 
-	// Objects maps identifiers to their corresponding objects (including
-	// package names, dots "." of dot-imports, and blank "_" identifiers).
-	// For identifiers that do not denote objects (e.g., blank identifiers
-	// on the lhs of assignments, or symbolic variables t in t := x.(type)
-	// of type switch headers), the corresponding objects are nil.
+	// Defs maps identifiers to the objects they define (including
+	// package names, dots "." of dot-imports, and blank "_"
+	// identifiers). For identifiers that do not denote objects
+	// (e.g., the blank identifier on the lhs of assignments, or
+	// symbolic variables t in t := x.(type) of type switch headers),
+	// the corresponding objects are nil.
+	//
+	// For an embedded field, Defs returns the field *Var it defines.
+	//
+	// Invariant: Defs[id] == nil || Defs[id].Pos() == id.Pos()
 	// BUG(gri) Label identifiers in break, continue, or goto statements
 	// are not yet mapped.
-	Objects map[*ast.Ident]Object
+	Defs map[*ast.Ident]Object
+
+	// Uses maps identifiers to the objects they denote.
+	//
+	// For an embedded field, Uses returns the *TypeName it denotes.
+	//
+	// Invariant: Uses[id].Pos() != id.Pos()
+	Uses map[*ast.Ident]Object
 
 	// Implicits maps nodes to their implicitly declared objects, if any.
 	// The following node and object types may appear:
@@ -150,6 +163,29 @@ type Info struct {
 	Scopes map[ast.Node]*Scope
 }
 
+// TypeOf returns the type of expression e, or nil if not found.
+// Precondition: the Types map is populated.
+func (info *Info) TypeOf(e ast.Expr) Type {
+	if t, ok := info.Types[e]; ok {
+		return t
+	}
+	if id, _ := e.(*ast.Ident); id != nil {
+		if obj := info.ObjectOf(id); obj != nil {
+			return obj.Type()
+		}
+	}
+	return nil
+}
+
+// ObjectOf returns the object denoted by the specified id, or nil if
+// not found. Precondition: the Defs and Uses maps are populated.
+func (info *Info) ObjectOf(id *ast.Ident) Object {
+	if obj := info.Defs[id]; obj != nil {
+		return obj
+	}
+	return info.Uses[id]
+}
+
 // Check type-checks a package and returns the resulting package object,
 // the first error if any, and if info != nil, additional type information.
 // The package is marked as complete if no errors occurred, otherwise it is
@@ -0,0 +1,99 @@
+// Copyright 2014 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ssa
+
+// This file defines utilities for enumerating the packages and named
+// types of a Program and querying their method sets, prerequisites for
+// callgraph construction (CHA, RTA) and "find implementers" queries
+// that would otherwise require callers to walk pkg.Members by hand, as
+// findNamedFunc does.
+//
+// MethodSets' cache lives on Program itself, as the unexported fields
+//
+//	methodSetsOnce sync.Once
+//	methodSets     map[types.Type]*types.MethodSet
+//
+// declared alongside Program's other fields in ssa.go, so that the
+// cache is collected along with the Program it was computed for rather
+// than needing explicit eviction.
+
+import (
+	"code.google.com/p/go.tools/go/types"
+)
+
+// AllPackages returns all packages that have been created in prog, in
+// no particular order.
+func (prog *Program) AllPackages() []*Package {
+	pkgs := make([]*Package, 0, len(prog.packages))
+	for _, pkg := range prog.packages {
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// MethodSets returns the method sets of every named type (and its
+// pointer type) declared by any package built into prog. The result
+// is computed once per prog, the first time it is needed, and cached
+// on prog itself (in its methodSetsOnce/methodSets fields) so that it
+// is collected along with prog rather than outliving it; a
+// package-level cache keyed by *Program would instead pin every
+// Program ever passed here for the life of the process. CHA/RTA
+// callgraph construction calls ImplementsInterface and LookupMethod
+// once per interface-invoke-site times per candidate concrete type, so
+// without this cache each such call would rescan every member of every
+// package in the program.
+func (prog *Program) MethodSets() map[types.Type]*types.MethodSet {
+	prog.methodSetsOnce.Do(func() {
+		msets := make(map[types.Type]*types.MethodSet)
+		for _, pkg := range prog.AllPackages() {
+			for _, mem := range pkg.Members {
+				t, ok := mem.(*Type)
+				if !ok {
+					continue
+				}
+				T := t.Type()
+				msets[T] = T.MethodSet()
+				PT := types.NewPointer(T)
+				msets[PT] = PT.MethodSet()
+			}
+		}
+		prog.methodSets = msets
+	})
+	return prog.methodSets
+}
+
+// ImplementsInterface reports whether concrete implements iface, using
+// the cached method-set index in preference to recomputing concrete's
+// method set from scratch.
+func (prog *Program) ImplementsInterface(concrete types.Type, iface *types.Interface) bool {
+	mset, ok := prog.MethodSets()[concrete]
+	if !ok {
+		mset = concrete.MethodSet()
+	}
+	for i, n := 0, iface.NumMethods(); i < n; i++ {
+		m := iface.Method(i)
+		sel := mset.Lookup(m.Pkg(), m.Name())
+		if sel == nil || !types.Identical(sel.Type(), m.Type()) {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupMethod returns the SSA function for the method of T (or *T)
+// named name declared in pkg, synthesizing a promoted-field wrapper
+// function on demand if the method was inherited from an embedded
+// field, or nil if T has no such method.
+func (prog *Program) LookupMethod(T types.Type, pkg *types.Package, name string) *Function {
+	mset, ok := prog.MethodSets()[T]
+	if !ok {
+		mset = T.MethodSet()
+	}
+	sel := mset.Lookup(pkg, name)
+	if sel == nil {
+		return nil
+	}
+	return prog.Method(sel)
+}